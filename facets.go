@@ -0,0 +1,97 @@
+package main
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "log"
+  "net/http"
+  "net/url"
+  "regexp"
+  "strings"
+)
+
+const (
+  resolveHandleURL = "https://bsky.social/xrpc/com.atproto.identity.resolveHandle"
+  // autoHashtag is appended to every post so the Al tag links posts together.
+  autoHashtag = "#Al"
+)
+
+var (
+  facetURLRegex     = regexp.MustCompile(`https?://[^\s]+`)
+  facetHashtagRegex = regexp.MustCompile(`#\w+`)
+  facetMentionRegex = regexp.MustCompile(`@[a-zA-Z0-9.-]+`)
+)
+
+// buildFacets scans text for URLs, hashtags, and @mentions and returns the AT
+// Protocol richtext facet array Bluesky needs to render them as clickable.
+// FindAllStringIndex returns UTF-8 byte offsets into text, which is what
+// index.byteStart/byteEnd require - ranging over a string by rune (as with a
+// plain `for range`) would silently misplace facets on any non-ASCII text.
+func buildFacets(ctx context.Context, text string) []map[string]interface{} {
+  facets := []map[string]interface{}{}
+
+  for _, m := range facetURLRegex.FindAllStringIndex(text, -1) {
+    facets = append(facets, facetEntry(m[0], m[1], map[string]interface{}{
+      "$type": "app.bsky.richtext.facet#link",
+      "uri":   text[m[0]:m[1]],
+    }))
+  }
+
+  for _, m := range facetHashtagRegex.FindAllStringIndex(text, -1) {
+    facets = append(facets, facetEntry(m[0], m[1], map[string]interface{}{
+      "$type": "app.bsky.richtext.facet#tag",
+      "tag":   strings.TrimPrefix(text[m[0]:m[1]], "#"),
+    }))
+  }
+
+  for _, m := range facetMentionRegex.FindAllStringIndex(text, -1) {
+    handle := strings.TrimPrefix(text[m[0]:m[1]], "@")
+    did, err := resolveHandle(ctx, handle)
+    if err != nil {
+      log.Printf("resolveHandle(%q): %v", handle, err)
+      continue
+    }
+    facets = append(facets, facetEntry(m[0], m[1], map[string]interface{}{
+      "$type": "app.bsky.richtext.facet#mention",
+      "did":   did,
+    }))
+  }
+
+  return facets
+}
+
+func facetEntry(byteStart, byteEnd int, feature map[string]interface{}) map[string]interface{} {
+  return map[string]interface{}{
+    "index": map[string]interface{}{
+      "byteStart": byteStart,
+      "byteEnd":   byteEnd,
+    },
+    "features": []map[string]interface{}{feature},
+  }
+}
+
+// resolveHandle looks up the DID behind a Bluesky handle via com.atproto.identity.resolveHandle.
+func resolveHandle(ctx context.Context, handle string) (string, error) {
+  reqURL := resolveHandleURL + "?handle=" + url.QueryEscape(handle)
+  req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+  if err != nil {
+    return "", fmt.Errorf("http.NewRequestWithContext(%q) = %w", reqURL, err)
+  }
+  resp, err := http.DefaultClient.Do(req)
+  if err != nil {
+    return "", fmt.Errorf("http.Get(%q) = %w", reqURL, err)
+  }
+  defer resp.Body.Close()
+
+  if resp.StatusCode != http.StatusOK {
+    return "", fmt.Errorf("resolveHandle(%q) status %d", handle, resp.StatusCode)
+  }
+  var result struct {
+    Did string `json:"did"`
+  }
+  if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+    return "", fmt.Errorf("failed to decode resolveHandle response: %w", err)
+  }
+  return result.Did, nil
+}
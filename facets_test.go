@@ -0,0 +1,45 @@
+package main
+
+import (
+  "context"
+  "testing"
+)
+
+func TestBuildFacetsURLAndHashtag(t *testing.T) {
+  text := "Check https://example.com/x for more #Al"
+  facets := buildFacets(context.Background(), text)
+
+  if len(facets) != 2 {
+    t.Fatalf("buildFacets(%q) returned %d facets, want 2", text, len(facets))
+  }
+
+  link := facets[0]
+  index := link["index"].(map[string]interface{})
+  start, end := index["byteStart"].(int), index["byteEnd"].(int)
+  if got := text[start:end]; got != "https://example.com/x" {
+    t.Errorf("link facet covers %q, want %q", got, "https://example.com/x")
+  }
+
+  tag := facets[1]
+  index = tag["index"].(map[string]interface{})
+  start, end = index["byteStart"].(int), index["byteEnd"].(int)
+  if got := text[start:end]; got != "#Al" {
+    t.Errorf("hashtag facet covers %q, want %q", got, "#Al")
+  }
+}
+
+func TestBuildFacetsByteOffsetsWithMultibyteText(t *testing.T) {
+  // "café" is 5 bytes (the é is 2 bytes in UTF-8), so the URL's byte
+  // offset must account for that rather than its rune count (4).
+  text := "café https://example.com"
+  facets := buildFacets(context.Background(), text)
+
+  if len(facets) != 1 {
+    t.Fatalf("buildFacets(%q) returned %d facets, want 1", text, len(facets))
+  }
+  index := facets[0]["index"].(map[string]interface{})
+  start, end := index["byteStart"].(int), index["byteEnd"].(int)
+  if got := text[start:end]; got != "https://example.com" {
+    t.Errorf("link facet covers %q, want %q", got, "https://example.com")
+  }
+}
@@ -0,0 +1,61 @@
+package feeds
+
+import (
+  "context"
+  "encoding/xml"
+  "fmt"
+)
+
+// atomSource fetches an Atom 1.0 feed.
+type atomSource struct {
+  url string
+}
+
+type atomDocument struct {
+  Entries []atomEntry `xml:"entry"`
+}
+type atomEntry struct {
+  Title   string     `xml:"title"`
+  Summary string     `xml:"summary"`
+  Links   []atomLink `xml:"link"`
+}
+type atomLink struct {
+  Href string `xml:"href,attr"`
+  Rel  string `xml:"rel,attr"`
+}
+
+// link returns the entry's "alternate" link (the human-readable page), falling
+// back to whatever link is present if none is explicitly marked "alternate".
+func (e atomEntry) link() string {
+  for _, l := range e.Links {
+    if l.Rel == "" || l.Rel == "alternate" {
+      return l.Href
+    }
+  }
+  if len(e.Links) > 0 {
+    return e.Links[0].Href
+  }
+  return ""
+}
+
+func (s *atomSource) Fetch(ctx context.Context) ([]Item, error) {
+  body, err := fetch(ctx, s.url)
+  if err != nil {
+    return nil, err
+  }
+
+  var doc atomDocument
+  if err := xml.Unmarshal(body, &doc); err != nil {
+    return nil, fmt.Errorf("xml.Unmarshal(%q) = %w", s.url, err)
+  }
+
+  items := make([]Item, 0, len(doc.Entries))
+  for _, entry := range doc.Entries {
+    items = append(items, Item{
+      Title:       entry.Title,
+      Link:        entry.link(),
+      Description: entry.Summary,
+    })
+  }
+  return items, nil
+}
@@ -0,0 +1,62 @@
+package feeds
+
+import (
+  "fmt"
+  "os"
+  "strings"
+
+  "gopkg.in/yaml.v3"
+)
+
+// SourceConfig describes one configured feed source.
+type SourceConfig struct {
+  URL string `yaml:"url"`
+  // Format is one of "rss", "atom", or "jsonfeed"; defaults to "rss".
+  Format string `yaml:"format"`
+  // KeywordRegex overrides the bot's global keyword regex for this source.
+  KeywordRegex string `yaml:"keywordRegex,omitempty"`
+  // Weight biases random selection toward this source's matching titles.
+  Weight int `yaml:"weight,omitempty"`
+  // Enabled defaults to true; set to false to disable a source without
+  // removing it from the config.
+  Enabled *bool `yaml:"enabled,omitempty"`
+}
+
+// IsEnabled reports whether the source should be fetched.
+func (sc SourceConfig) IsEnabled() bool {
+  return sc.Enabled == nil || *sc.Enabled
+}
+
+// Config is the top-level feeds configuration file.
+type Config struct {
+  Sources []SourceConfig `yaml:"sources"`
+}
+
+// LoadConfig reads and parses a feeds config file. YAML is a superset of
+// JSON, so the same parser handles both `.yaml` and `.json` config files.
+func LoadConfig(path string) (*Config, error) {
+  data, err := os.ReadFile(path)
+  if err != nil {
+    return nil, fmt.Errorf("os.ReadFile(%q) = %w", path, err)
+  }
+  var cfg Config
+  if err := yaml.Unmarshal(data, &cfg); err != nil {
+    return nil, fmt.Errorf("yaml.Unmarshal(%q) = %w", path, err)
+  }
+  return &cfg, nil
+}
+
+// NewSource builds the Source implementation for a configured feed, selected
+// by its format hint.
+func NewSource(sc SourceConfig) (Source, error) {
+  switch strings.ToLower(sc.Format) {
+  case "", "rss":
+    return &rssSource{url: sc.URL}, nil
+  case "atom":
+    return &atomSource{url: sc.URL}, nil
+  case "jsonfeed":
+    return &jsonFeedSource{url: sc.URL}, nil
+  default:
+    return nil, fmt.Errorf("unknown feed format %q for %s", sc.Format, sc.URL)
+  }
+}
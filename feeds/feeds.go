@@ -0,0 +1,37 @@
+// Package feeds implements a pluggable set of syndication feed sources (RSS
+// 2.0, Atom 1.0, JSON Feed 1.1) that can be configured without recompiling
+// the bot and fetched concurrently.
+package feeds
+
+import (
+  "context"
+  "fmt"
+  "io"
+  "net/http"
+)
+
+// Item is a single entry from a feed source, normalized across formats.
+type Item struct {
+  Title       string
+  Link        string
+  Description string
+}
+
+// Source fetches the current items from a single feed.
+type Source interface {
+  Fetch(ctx context.Context) ([]Item, error)
+}
+
+// fetch performs a context-aware GET and returns the raw response body.
+func fetch(ctx context.Context, url string) ([]byte, error) {
+  req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+  if err != nil {
+    return nil, fmt.Errorf("http.NewRequestWithContext(%q) = %w", url, err)
+  }
+  resp, err := http.DefaultClient.Do(req)
+  if err != nil {
+    return nil, fmt.Errorf("http.Get(%q) = %w", url, err)
+  }
+  defer resp.Body.Close()
+  return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,48 @@
+package feeds
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+)
+
+// jsonFeedSource fetches a JSON Feed 1.1 feed (https://www.jsonfeed.org/version/1.1/).
+type jsonFeedSource struct {
+  url string
+}
+
+type jsonFeedDocument struct {
+  Items []jsonFeedItem `json:"items"`
+}
+type jsonFeedItem struct {
+  Title       string `json:"title"`
+  URL         string `json:"url"`
+  Summary     string `json:"summary"`
+  ContentText string `json:"content_text"`
+}
+
+func (s *jsonFeedSource) Fetch(ctx context.Context) ([]Item, error) {
+  body, err := fetch(ctx, s.url)
+  if err != nil {
+    return nil, err
+  }
+
+  var doc jsonFeedDocument
+  if err := json.Unmarshal(body, &doc); err != nil {
+    return nil, fmt.Errorf("json.Unmarshal(%q) = %w", s.url, err)
+  }
+
+  items := make([]Item, 0, len(doc.Items))
+  for _, item := range doc.Items {
+    description := item.Summary
+    if description == "" {
+      description = item.ContentText
+    }
+    items = append(items, Item{
+      Title:       item.Title,
+      Link:        item.URL,
+      Description: description,
+    })
+  }
+  return items, nil
+}
@@ -0,0 +1,46 @@
+package feeds
+
+import (
+  "context"
+  "encoding/xml"
+  "fmt"
+)
+
+// rssSource fetches an RSS 2.0 feed.
+type rssSource struct {
+  url string
+}
+
+type rssDocument struct {
+  Channel rssChannel `xml:"channel"`
+}
+type rssChannel struct {
+  Item []rssItem `xml:"item"`
+}
+type rssItem struct {
+  Title       string `xml:"title"`
+  Link        string `xml:"link"`
+  Description string `xml:"description"`
+}
+
+func (s *rssSource) Fetch(ctx context.Context) ([]Item, error) {
+  body, err := fetch(ctx, s.url)
+  if err != nil {
+    return nil, err
+  }
+
+  var doc rssDocument
+  if err := xml.Unmarshal(body, &doc); err != nil {
+    return nil, fmt.Errorf("xml.Unmarshal(%q) = %w", s.url, err)
+  }
+
+  items := make([]Item, 0, len(doc.Channel.Item))
+  for _, item := range doc.Channel.Item {
+    items = append(items, Item{
+      Title:       item.Title,
+      Link:        item.Link,
+      Description: item.Description,
+    })
+  }
+  return items, nil
+}
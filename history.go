@@ -0,0 +1,336 @@
+package main
+
+import (
+  "context"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "io"
+  "os"
+  "regexp"
+  "strings"
+  "time"
+
+  "cloud.google.com/go/storage"
+  "google.golang.org/api/googleapi"
+)
+
+const (
+  // historyRetention is how long a posted headline counts as "recent" for dedup purposes.
+  historyRetention = 30 * 24 * time.Hour
+  // headlineShingleOverlap is the Jaccard threshold (on 2-word shingles) at or above which two
+  // headlines are considered the same story, even if the wording differs slightly.
+  headlineShingleOverlap = 0.85
+
+  localHistoryPath  = "./history.json"
+  historyBucketEnv  = "HISTORY_BUCKET"
+  historyObjectName = "history.json"
+
+  // maxHistorySaveAttempts bounds the load-mutate-save retry loop used to
+  // recover from a generation conflict (see errGenerationConflict).
+  maxHistorySaveAttempts = 3
+)
+
+// errGenerationConflict is returned by writeGCSObject when another writer
+// updated the object since we read it (production/GCS only - local disk
+// writes can't detect this). Callers retry by reloading and reapplying
+// their mutation.
+var errGenerationConflict = errors.New("object generation changed since read")
+
+var (
+  nonWordRegex    = regexp.MustCompile(`[^a-z0-9\s]+`)
+  whitespaceRegex = regexp.MustCompile(`\s+`)
+)
+
+// postedHeadline is one entry in the dedup history.
+type postedHeadline struct {
+  Normalized string    `json:"normalized"`
+  Title      string    `json:"title"`
+  PostedAt   time.Time `json:"postedAt"`
+}
+
+// postedImageHash is one entry in the image dedup history.
+type postedImageHash struct {
+  Hash     uint64    `json:"hash"`
+  PostedAt time.Time `json:"postedAt"`
+}
+
+// postHistory is the recently-posted history used to dedup getPostBody() and getImage().
+type postHistory struct {
+  Headlines []postedHeadline  `json:"headlines"`
+  Images    []postedImageHash `json:"images"`
+
+  // generation is the GCS object generation this history was loaded at (0 if
+  // loaded from local disk, or if the object didn't exist yet). save() uses
+  // it as a precondition so two overlapping invocations can't silently
+  // clobber each other's writes; see errGenerationConflict.
+  generation int64
+}
+
+// normalizeTitle lowercases, strips punctuation, and collapses whitespace so headlines
+// from different outlets compare equal when they're really reporting the same story.
+func normalizeTitle(title string) string {
+  lower := strings.ToLower(title)
+  stripped := nonWordRegex.ReplaceAllString(lower, "")
+  return strings.TrimSpace(whitespaceRegex.ReplaceAllString(stripped, " "))
+}
+
+// shingles returns the set of 2-word shingles for a normalized title, used for
+// near-duplicate detection via Jaccard similarity.
+func shingles(normalized string) map[string]bool {
+  words := strings.Fields(normalized)
+  set := map[string]bool{}
+  if len(words) < 2 {
+    for _, w := range words {
+      set[w] = true
+    }
+    return set
+  }
+  for i := 0; i < len(words)-1; i++ {
+    set[words[i]+" "+words[i+1]] = true
+  }
+  return set
+}
+
+// jaccard returns the Jaccard similarity between two shingle sets.
+func jaccard(a, b map[string]bool) float64 {
+  if len(a) == 0 || len(b) == 0 {
+    return 0
+  }
+  intersection := 0
+  for s := range a {
+    if b[s] {
+      intersection++
+    }
+  }
+  union := len(a) + len(b) - intersection
+  if union == 0 {
+    return 0
+  }
+  return float64(intersection) / float64(union)
+}
+
+// loadHistory reads the persisted post history, pruning anything older than the
+// retention window. A missing history file/object is treated as empty history.
+func loadHistory(ctx context.Context) (*postHistory, error) {
+  data, generation, err := readHistoryStore(ctx)
+  if err != nil {
+    return nil, fmt.Errorf("readHistoryStore() = %w", err)
+  }
+  h := &postHistory{generation: generation}
+  if len(data) > 0 {
+    if err := json.Unmarshal(data, h); err != nil {
+      return nil, fmt.Errorf("failed to decode post history: %w", err)
+    }
+  }
+  h.prune()
+  return h, nil
+}
+
+// isDuplicateHeadline reports whether title exactly or near-duplicates (>=85%
+// Jaccard overlap on 2-word shingles) a headline posted within the retention window.
+func (h *postHistory) isDuplicateHeadline(title string) bool {
+  normalized := normalizeTitle(title)
+  candidate := shingles(normalized)
+  for _, entry := range h.Headlines {
+    if entry.Normalized == normalized {
+      return true
+    }
+    if jaccard(candidate, shingles(entry.Normalized)) >= headlineShingleOverlap {
+      return true
+    }
+  }
+  return false
+}
+
+// recordHeadline appends a posted headline and persists the updated history.
+func (h *postHistory) recordHeadline(ctx context.Context, title string) error {
+  h.Headlines = append(h.Headlines, postedHeadline{
+    Normalized: normalizeTitle(title),
+    Title:      title,
+    PostedAt:   time.Now().UTC(),
+  })
+  h.prune()
+  return h.save(ctx)
+}
+
+// isRecentImage reports whether hash is within threshold Hamming distance of any
+// image hash posted within the retention window.
+func (h *postHistory) isRecentImage(hash uint64, threshold int) bool {
+  for _, entry := range h.Images {
+    if hammingDistance(hash, entry.Hash) < threshold {
+      return true
+    }
+  }
+  return false
+}
+
+// recordImage appends a posted image hash and persists the updated history.
+func (h *postHistory) recordImage(ctx context.Context, hash uint64) error {
+  h.Images = append(h.Images, postedImageHash{
+    Hash:     hash,
+    PostedAt: time.Now().UTC(),
+  })
+  h.prune()
+  return h.save(ctx)
+}
+
+// prune drops entries older than the retention window.
+func (h *postHistory) prune() {
+  cutoff := time.Now().UTC().Add(-historyRetention)
+
+  keptHeadlines := h.Headlines[:0]
+  for _, entry := range h.Headlines {
+    if entry.PostedAt.After(cutoff) {
+      keptHeadlines = append(keptHeadlines, entry)
+    }
+  }
+  h.Headlines = keptHeadlines
+
+  keptImages := h.Images[:0]
+  for _, entry := range h.Images {
+    if entry.PostedAt.After(cutoff) {
+      keptImages = append(keptImages, entry)
+    }
+  }
+  h.Images = keptImages
+}
+
+func (h *postHistory) save(ctx context.Context) error {
+  data, err := json.Marshal(h)
+  if err != nil {
+    return fmt.Errorf("failed to encode post history: %w", err)
+  }
+  return writeHistoryStore(ctx, data, h.generation)
+}
+
+// recordPostedHeadline persists title to the post history after a successful post
+// so future runs don't repeat it or a near-duplicate. Two Cloud Run invocations
+// can overlap and load the same history, so a generation conflict on save is
+// retried by reloading and reapplying the append against the latest history.
+func recordPostedHeadline(title string) error {
+  return withHistoryRetry(func(h *postHistory) error {
+    return h.recordHeadline(context.Background(), title)
+  })
+}
+
+// recordPostedImage persists hash to the post history after a successful post so
+// future runs avoid picking a visually similar image again too soon. See
+// recordPostedHeadline for why this retries on a generation conflict.
+func recordPostedImage(hash uint64) error {
+  return withHistoryRetry(func(h *postHistory) error {
+    return h.recordImage(context.Background(), hash)
+  })
+}
+
+// withHistoryRetry loads the current history and applies mutate to it,
+// making up to maxHistorySaveAttempts attempts in total while the save keeps
+// losing a race with an overlapping invocation (errGenerationConflict).
+func withHistoryRetry(mutate func(*postHistory) error) error {
+  ctx := context.Background()
+  var lastErr error
+  for attempt := 0; attempt < maxHistorySaveAttempts; attempt++ {
+    h, err := loadHistory(ctx)
+    if err != nil {
+      return fmt.Errorf("loadHistory() = %w", err)
+    }
+    err = mutate(h)
+    if err == nil {
+      return nil
+    }
+    if !errors.Is(err, errGenerationConflict) {
+      return err
+    }
+    lastErr = err
+  }
+  return fmt.Errorf("giving up after %d attempts: %w", maxHistorySaveAttempts, lastErr)
+}
+
+// readHistoryStore loads the raw history bytes (and, for the GCS path, the
+// object generation they were read at - see errGenerationConflict) from local
+// disk in dev, or from GCS when running in production (Cloud Run).
+func readHistoryStore(ctx context.Context) ([]byte, int64, error) {
+  if os.Getenv("ENVIRONMENT") == "production" {
+    return readGCSObject(ctx, os.Getenv(historyBucketEnv), historyObjectName)
+  }
+  data, err := os.ReadFile(localHistoryPath)
+  if os.IsNotExist(err) {
+    return nil, 0, nil
+  }
+  return data, 0, err
+}
+
+// writeHistoryStore persists the raw history bytes to local disk in dev, or to
+// GCS when running in production (Cloud Run). Local disk writes aren't
+// protected against concurrent invocations; only the GCS path is.
+func writeHistoryStore(ctx context.Context, data []byte, generation int64) error {
+  if os.Getenv("ENVIRONMENT") == "production" {
+    return writeGCSObject(ctx, os.Getenv(historyBucketEnv), historyObjectName, data, generation)
+  }
+  return os.WriteFile(localHistoryPath, data, 0644)
+}
+
+// readGCSObject reads object from bucket, returning (nil, 0, nil) if it doesn't
+// exist yet. The returned generation identifies the exact version read, for use
+// as a precondition on a subsequent writeGCSObject.
+func readGCSObject(ctx context.Context, bucket, object string) ([]byte, int64, error) {
+  if bucket == "" {
+    return nil, 0, fmt.Errorf("GCS bucket not set for object %q", object)
+  }
+  client, err := storage.NewClient(ctx)
+  if err != nil {
+    return nil, 0, fmt.Errorf("storage.NewClient() = %w", err)
+  }
+  defer client.Close()
+
+  r, err := client.Bucket(bucket).Object(object).NewReader(ctx)
+  if err == storage.ErrObjectNotExist {
+    return nil, 0, nil
+  }
+  if err != nil {
+    return nil, 0, fmt.Errorf("NewReader(%q) = %w", object, err)
+  }
+  defer r.Close()
+  data, err := io.ReadAll(r)
+  if err != nil {
+    return nil, 0, err
+  }
+  return data, r.Attrs.Generation, nil
+}
+
+// writeGCSObject writes data to object in bucket, using generation as a
+// precondition: generation 0 requires the object not exist yet, otherwise the
+// write only succeeds if the object's generation still matches. This stops
+// two overlapping invocations from silently clobbering each other's write;
+// a losing writer gets errGenerationConflict back instead.
+func writeGCSObject(ctx context.Context, bucket, object string, data []byte, generation int64) error {
+  if bucket == "" {
+    return fmt.Errorf("GCS bucket not set for object %q", object)
+  }
+  client, err := storage.NewClient(ctx)
+  if err != nil {
+    return fmt.Errorf("storage.NewClient() = %w", err)
+  }
+  defer client.Close()
+
+  obj := client.Bucket(bucket).Object(object)
+  if generation == 0 {
+    obj = obj.If(storage.Conditions{DoesNotExist: true})
+  } else {
+    obj = obj.If(storage.Conditions{GenerationMatch: generation})
+  }
+
+  w := obj.NewWriter(ctx)
+  if _, err := w.Write(data); err != nil {
+    w.Close()
+    return fmt.Errorf("Write(%q) = %w", object, err)
+  }
+  if err := w.Close(); err != nil {
+    var apiErr *googleapi.Error
+    if errors.As(err, &apiErr) && apiErr.Code == 412 {
+      return errGenerationConflict
+    }
+    return fmt.Errorf("Close(%q) = %w", object, err)
+  }
+  return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import "testing"
+
+func TestNormalizeTitle(t *testing.T) {
+  tests := []struct {
+    title string
+    want  string
+  }{
+    {"OpenAI Ships GPT-5!", "openai ships gpt5"},
+    {"  Extra   Whitespace  ", "extra whitespace"},
+    {"Already normal", "already normal"},
+  }
+  for _, tt := range tests {
+    if got := normalizeTitle(tt.title); got != tt.want {
+      t.Errorf("normalizeTitle(%q) = %q, want %q", tt.title, got, tt.want)
+    }
+  }
+}
+
+func TestJaccard(t *testing.T) {
+  tests := []struct {
+    name string
+    a, b string
+    want float64
+  }{
+    {"identical", "one two three", "one two three", 1},
+    {"disjoint", "one two three", "four five six", 0},
+    {"empty a", "", "one two", 0},
+  }
+  for _, tt := range tests {
+    got := jaccard(shingles(tt.a), shingles(tt.b))
+    if got != tt.want {
+      t.Errorf("%s: jaccard(%q, %q) = %v, want %v", tt.name, tt.a, tt.b, got, tt.want)
+    }
+  }
+}
+
+func TestIsDuplicateHeadline(t *testing.T) {
+  original := "Researchers at the university today announced a major breakthrough in artificial intelligence model training techniques"
+  nearDuplicate := "Researchers at the university today announced a major breakthrough in artificial intelligence model training methods"
+
+  h := &postHistory{
+    Headlines: []postedHeadline{
+      {Normalized: normalizeTitle(original)},
+    },
+  }
+
+  if !h.isDuplicateHeadline(original) {
+    t.Error("exact match not detected as duplicate")
+  }
+  if !h.isDuplicateHeadline(nearDuplicate) {
+    t.Error("near-duplicate (one word swapped) not detected as duplicate")
+  }
+  if h.isDuplicateHeadline("Google releases a totally different product") {
+    t.Error("unrelated headline incorrectly flagged as duplicate")
+  }
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+  "bytes"
+  "fmt"
+  "image/color"
+  "image/jpeg"
+  "log"
+  "math/bits"
+  "os"
+  "path/filepath"
+  "strconv"
+  "strings"
+  "sync"
+)
+
+const (
+  // imageHashSize is the side length of the downscaled grayscale grid used
+  // to compute the average hash (a 64-bit hash for an 8x8 grid).
+  imageHashSize = 8
+
+  // defaultImageHashDistanceThreshold is the default minimum Hamming distance an
+  // image's hash must have from every recently-posted hash to be selectable.
+  defaultImageHashDistanceThreshold = 5
+  imageHashDistanceThresholdEnv     = "IMAGE_HASH_DISTANCE_THRESHOLD"
+)
+
+var (
+  imageHashCache     map[string]uint64
+  imageHashCacheOnce sync.Once
+)
+
+// averageHash computes a 64-bit average hash (aHash) for a JPEG image: downscale
+// to an 8x8 grayscale grid, then emit one bit per cell for whether it's above or
+// below the grid's mean luminance. Similar images produce hashes with a small
+// Hamming distance, even after re-encoding or minor cropping.
+func averageHash(data []byte) (uint64, error) {
+  img, err := jpeg.Decode(bytes.NewReader(data))
+  if err != nil {
+    return 0, fmt.Errorf("jpeg.Decode() = %w", err)
+  }
+
+  var gray [imageHashSize * imageHashSize]float64
+  bounds := img.Bounds()
+  w, h := bounds.Dx(), bounds.Dy()
+  for y := 0; y < imageHashSize; y++ {
+    srcY := bounds.Min.Y + y*h/imageHashSize
+    for x := 0; x < imageHashSize; x++ {
+      srcX := bounds.Min.X + x*w/imageHashSize
+      gray[y*imageHashSize+x] = luminance(img.At(srcX, srcY))
+    }
+  }
+
+  var sum float64
+  for _, v := range gray {
+    sum += v
+  }
+  mean := sum / float64(len(gray))
+
+  var hash uint64
+  for _, v := range gray {
+    hash <<= 1
+    if v > mean {
+      hash |= 1
+    }
+  }
+  return hash, nil
+}
+
+// luminance returns the Rec. 601 grayscale value of a pixel, 0-255.
+func luminance(c color.Color) float64 {
+  r, g, b, _ := c.RGBA()
+  return 0.299*float64(r>>8) + 0.587*float64(g>>8) + 0.114*float64(b>>8)
+}
+
+// hammingDistance returns the number of differing bits between two hashes.
+func hammingDistance(a, b uint64) int {
+  return bits.OnesCount64(a ^ b)
+}
+
+// imageHashThreshold returns the configured minimum Hamming distance, falling
+// back to defaultImageHashDistanceThreshold if unset or invalid.
+func imageHashThreshold() int {
+  raw := os.Getenv(imageHashDistanceThresholdEnv)
+  if raw == "" {
+    return defaultImageHashDistanceThreshold
+  }
+  threshold, err := strconv.Atoi(raw)
+  if err != nil {
+    log.Printf("invalid %s=%q, using default: %v", imageHashDistanceThresholdEnv, raw, err)
+    return defaultImageHashDistanceThreshold
+  }
+  return threshold
+}
+
+// loadImageHashCache computes the average hash of every jpg under ./images once
+// per process and caches it in memory, so repeat Cloud Run invocations on a
+// warm container don't re-hash the whole directory every time.
+func loadImageHashCache() map[string]uint64 {
+  imageHashCacheOnce.Do(func() {
+    cache := map[string]uint64{}
+    err := filepath.Walk("./images", func(path string, info os.FileInfo, err error) error {
+      if err != nil || info.IsDir() || !strings.HasSuffix(info.Name(), ".jpg") {
+        return nil
+      }
+      data, err := os.ReadFile(path)
+      if err != nil {
+        log.Printf("os.ReadFile(%q): %v", path, err)
+        return nil
+      }
+      hash, err := averageHash(data)
+      if err != nil {
+        log.Printf("averageHash(%q): %v", path, err)
+        return nil
+      }
+      cache[path] = hash
+      return nil
+    })
+    if err != nil {
+      log.Printf("filepath.Walk(./images): %v", err)
+    }
+    imageHashCache = cache
+  })
+  return imageHashCache
+}
@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestHammingDistance(t *testing.T) {
+  tests := []struct {
+    name string
+    a, b uint64
+    want int
+  }{
+    {"identical", 0xabcd1234, 0xabcd1234, 0},
+    {"all bits differ", 0, ^uint64(0), 64},
+    {"single bit differs", 0b0000, 0b0001, 1},
+    {"all four bits differ", 0b1010, 0b0101, 4},
+  }
+  for _, tt := range tests {
+    if got := hammingDistance(tt.a, tt.b); got != tt.want {
+      t.Errorf("%s: hammingDistance(%b, %b) = %d, want %d", tt.name, tt.a, tt.b, got, tt.want)
+    }
+  }
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+  "bytes"
+  "fmt"
+  "image"
+  "image/jpeg"
+  _ "image/png"
+  "log"
+
+  "github.com/rwcarlsen/goexif/exif"
+  "golang.org/x/image/draw"
+  _ "golang.org/x/image/webp"
+)
+
+const (
+  maxBlobBytes  = 1_000_000 // Bluesky's blob size limit
+  maxDimension  = 1000      // Bluesky's max embed image dimension
+  jpegQuality   = 85
+)
+
+// preparedImage is a re-encoded, upload-ready image.
+type preparedImage struct {
+  Data        []byte
+  ContentType string
+  Width       int
+  Height      int
+}
+
+// prepareImage decodes a jpeg/png/webp image, auto-orients it per its EXIF
+// orientation tag, downscales it to Bluesky's 1000px max dimension, and
+// re-encodes it as JPEG at quality 85 - stripping all other metadata along
+// the way. It refuses to return anything over the 1MB blob limit.
+func prepareImage(data []byte) (*preparedImage, error) {
+  img, format, err := image.Decode(bytes.NewReader(data))
+  if err != nil {
+    return nil, fmt.Errorf("image.Decode() = %w", err)
+  }
+  original := img.Bounds()
+
+  if orientation := exifOrientation(data); orientation > 1 {
+    img = applyOrientation(img, orientation)
+  }
+  img = downscale(img, maxDimension)
+
+  encoded, err := encodeJPEG(img, jpegQuality)
+  if err != nil {
+    return nil, fmt.Errorf("encodeJPEG() = %w", err)
+  }
+  if len(encoded) > maxBlobBytes {
+    return nil, fmt.Errorf("image still %d bytes after compression, over the %d byte blob limit", len(encoded), maxBlobBytes)
+  }
+
+  bounds := img.Bounds()
+  log.Printf("prepareImage: %s %dx%d (%d bytes) -> jpeg %dx%d (%d bytes)",
+    format, original.Dx(), original.Dy(), len(data), bounds.Dx(), bounds.Dy(), len(encoded))
+
+  return &preparedImage{
+    Data:        encoded,
+    ContentType: "image/jpeg",
+    Width:       bounds.Dx(),
+    Height:      bounds.Dy(),
+  }, nil
+}
+
+// exifOrientation returns the image's EXIF orientation tag (1-8), or 1 (the
+// "normal" orientation) if the image has no EXIF data or the tag is absent.
+func exifOrientation(data []byte) int {
+  x, err := exif.Decode(bytes.NewReader(data))
+  if err != nil {
+    return 1
+  }
+  tag, err := x.Get(exif.Orientation)
+  if err != nil {
+    return 1
+  }
+  v, err := tag.Int(0)
+  if err != nil {
+    return 1
+  }
+  return v
+}
+
+// applyOrientation rotates/flips img per the EXIF orientation spec (values 2-8).
+func applyOrientation(img image.Image, orientation int) image.Image {
+  switch orientation {
+  case 2:
+    return flipH(img)
+  case 3:
+    return rotate180(img)
+  case 4:
+    return flipV(img)
+  case 5:
+    return flipH(rotate90(img))
+  case 6:
+    return rotate90(img)
+  case 7:
+    return flipH(rotate270(img))
+  case 8:
+    return rotate270(img)
+  default:
+    return img
+  }
+}
+
+func rotate90(img image.Image) image.Image {
+  b := img.Bounds()
+  dst := image.NewNRGBA(image.Rect(0, 0, b.Dy(), b.Dx()))
+  for y := b.Min.Y; y < b.Max.Y; y++ {
+    for x := b.Min.X; x < b.Max.X; x++ {
+      dst.Set(b.Max.Y-1-y, x-b.Min.X, img.At(x, y))
+    }
+  }
+  return dst
+}
+
+func rotate270(img image.Image) image.Image {
+  b := img.Bounds()
+  dst := image.NewNRGBA(image.Rect(0, 0, b.Dy(), b.Dx()))
+  for y := b.Min.Y; y < b.Max.Y; y++ {
+    for x := b.Min.X; x < b.Max.X; x++ {
+      dst.Set(y-b.Min.Y, b.Max.X-1-x, img.At(x, y))
+    }
+  }
+  return dst
+}
+
+func rotate180(img image.Image) image.Image {
+  b := img.Bounds()
+  dst := image.NewNRGBA(b)
+  for y := b.Min.Y; y < b.Max.Y; y++ {
+    for x := b.Min.X; x < b.Max.X; x++ {
+      dst.Set(b.Max.X-1-x, b.Max.Y-1-y, img.At(x, y))
+    }
+  }
+  return dst
+}
+
+func flipH(img image.Image) image.Image {
+  b := img.Bounds()
+  dst := image.NewNRGBA(b)
+  for y := b.Min.Y; y < b.Max.Y; y++ {
+    for x := b.Min.X; x < b.Max.X; x++ {
+      dst.Set(b.Max.X-1-x, y, img.At(x, y))
+    }
+  }
+  return dst
+}
+
+func flipV(img image.Image) image.Image {
+  b := img.Bounds()
+  dst := image.NewNRGBA(b)
+  for y := b.Min.Y; y < b.Max.Y; y++ {
+    for x := b.Min.X; x < b.Max.X; x++ {
+      dst.Set(x, b.Max.Y-1-y, img.At(x, y))
+    }
+  }
+  return dst
+}
+
+// downscale shrinks img, preserving aspect ratio, so neither dimension
+// exceeds maxDim. Images already within bounds are returned unchanged.
+func downscale(img image.Image, maxDim int) image.Image {
+  b := img.Bounds()
+  w, h := b.Dx(), b.Dy()
+  if w <= maxDim && h <= maxDim {
+    return img
+  }
+
+  scale := float64(maxDim) / float64(w)
+  if h > w {
+    scale = float64(maxDim) / float64(h)
+  }
+  newW := int(float64(w) * scale)
+  newH := int(float64(h) * scale)
+
+  dst := image.NewRGBA(image.Rect(0, 0, newW, newH))
+  draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
+  return dst
+}
+
+func encodeJPEG(img image.Image, quality int) ([]byte, error) {
+  var buf bytes.Buffer
+  if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+    return nil, err
+  }
+  return buf.Bytes(), nil
+}
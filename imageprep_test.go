@@ -0,0 +1,92 @@
+package main
+
+import (
+  "image"
+  "image/color"
+  "testing"
+)
+
+// Four distinct opaque colors at the corners of a 2x2 test image, named so
+// expected-output tables read as "top-left", "top-right", etc.
+var (
+  pxA = color.NRGBA{R: 0xff, G: 0x00, B: 0x00, A: 0xff} // (0,0)
+  pxB = color.NRGBA{R: 0x00, G: 0xff, B: 0x00, A: 0xff} // (1,0)
+  pxC = color.NRGBA{R: 0x00, G: 0x00, B: 0xff, A: 0xff} // (0,1)
+  pxD = color.NRGBA{R: 0xff, G: 0xff, B: 0x00, A: 0xff} // (1,1)
+)
+
+// grid is the 2x2 corner layout of an image, in (0,0),(1,0),(0,1),(1,1) order.
+type grid [4]color.NRGBA
+
+func testImage() image.Image {
+  img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+  img.Set(0, 0, pxA)
+  img.Set(1, 0, pxB)
+  img.Set(0, 1, pxC)
+  img.Set(1, 1, pxD)
+  return img
+}
+
+func gridOf(img image.Image) grid {
+  b := img.Bounds()
+  if b.Dx() != 2 || b.Dy() != 2 {
+    panic("gridOf: expected a 2x2 image")
+  }
+  var g grid
+  at := func(x, y int) color.NRGBA {
+    r, gr, bl, a := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+    return color.NRGBA{R: uint8(r >> 8), G: uint8(gr >> 8), B: uint8(bl >> 8), A: uint8(a >> 8)}
+  }
+  g[0] = at(0, 0)
+  g[1] = at(1, 0)
+  g[2] = at(0, 1)
+  g[3] = at(1, 1)
+  return g
+}
+
+func TestApplyOrientation(t *testing.T) {
+  tests := []struct {
+    orientation int
+    want        grid
+  }{
+    {1, grid{pxA, pxB, pxC, pxD}}, // no-op
+    {2, grid{pxB, pxA, pxD, pxC}}, // flipH
+    {3, grid{pxD, pxC, pxB, pxA}}, // rotate180
+    {4, grid{pxC, pxD, pxA, pxB}}, // flipV
+    {5, grid{pxA, pxC, pxB, pxD}}, // flipH(rotate90)
+    {6, grid{pxC, pxA, pxD, pxB}}, // rotate90
+    {7, grid{pxD, pxB, pxC, pxA}}, // flipH(rotate270)
+    {8, grid{pxB, pxD, pxA, pxC}}, // rotate270
+  }
+
+  for _, tt := range tests {
+    got := gridOf(applyOrientation(testImage(), tt.orientation))
+    if got != tt.want {
+      t.Errorf("applyOrientation(orientation=%d) = %v, want %v", tt.orientation, got, tt.want)
+    }
+  }
+}
+
+// rotate90 and rotate270 are each a single 90-degree turn, so applying either
+// one twice must match rotate180 applied once - this is the invariant the
+// original rotate270 (a transpose mis-implemented as a rotation) violated.
+func TestRotate90AndRotate270AreQuarterTurns(t *testing.T) {
+  want := gridOf(rotate180(testImage()))
+
+  if got := gridOf(rotate90(rotate90(testImage()))); got != want {
+    t.Errorf("rotate90 twice = %v, want rotate180 = %v", got, want)
+  }
+  if got := gridOf(rotate270(rotate270(testImage()))); got != want {
+    t.Errorf("rotate270 twice = %v, want rotate180 = %v", got, want)
+  }
+}
+
+func TestRotateSwapsBounds(t *testing.T) {
+  img := image.NewNRGBA(image.Rect(0, 0, 3, 5))
+  for _, rot := range []func(image.Image) image.Image{rotate90, rotate270} {
+    b := rot(img).Bounds()
+    if b.Dx() != 5 || b.Dy() != 3 {
+      t.Errorf("rotated bounds = %dx%d, want 5x3", b.Dx(), b.Dy())
+    }
+  }
+}
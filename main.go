@@ -2,24 +2,27 @@ package main
 
 import (
   "bytes"
+  "context"
   "encoding/json"
-  "encoding/xml"
   "fmt"
   "log"
-  "io"
   "math/rand"
   "net/http"
   "os"
   "path/filepath"
   "regexp"
   "strings"
+  "sync"
   "time"
+
+  "al-news/feeds"
 )
 
 // AuthResponse represents the authentication response from Bluesky
 type AuthResponse struct {
-  AccessJwt string `json:"accessJwt"`
-  Did       string `json:"did"`
+  AccessJwt  string `json:"accessJwt"`
+  RefreshJwt string `json:"refreshJwt"`
+  Did        string `json:"did"`
 }
 
 // ErrorResponse represents the error response structure from Bluesky
@@ -75,15 +78,11 @@ func AlNewsPost() {
     log.Fatal("BLUESKY_PASSWORD environment variable not set")
   }
 
-  // Authenticate and obtain access token
-  authResponse, err := authenticate(username, password)
-  if err != nil {
-    log.Fatalf("Authentication failed: %v", err)
-  }
-
-  imageData, imageName, err := getImage()
+  // Get a cached session (refreshing or re-authenticating as needed) instead
+  // of hitting createSession on every invocation.
+  session, err := GetSession(context.Background(), username, password)
   if err != nil {
-    log.Fatalf("getImage() = %v", err)
+    log.Fatalf("GetSession() = %v", err)
   }
 
   postBody, err := getPostBody()
@@ -91,19 +90,29 @@ func AlNewsPost() {
     log.Fatalf("getPostBody() = %v", err)
   }
 
-  // Attach image a la https://docs.bsky.app/docs/advanced-guides/posts#images-embeds
-  // Post image and message using access token
-  log.Printf("Uploading %v byte image of %v", len(imageData), imageName)
-  imageBlob, err := uploadImage(authResponse.AccessJwt, authResponse.Did, imageData)
+  // Prefer a rich-link OpenGraph card for the article; fall back to a random
+  // local image (a la https://docs.bsky.app/docs/advanced-guides/posts#images-embeds)
+  // when og-scrape fails or is disabled.
+  embed, imageHash, err := buildEmbed(session, postBody.Link)
   if err != nil {
-    log.Fatalf("uploadImage() = %v", err)
+    log.Fatalf("buildEmbed() = %v", err)
   }
-  log.Printf("Posting %q", postBody)
-  err = postMessage(authResponse.AccessJwt, authResponse.Did, postBody, imageBlob, imageName)
+
+  log.Printf("Posting %q", postBody.Body)
+  err = postMessage(session, postBody.Body, embed)
   if err != nil {
     log.Fatalf("postMessage() = %v", err)
   }
 
+  if err := recordPostedHeadline(postBody.RawTitle); err != nil {
+    log.Printf("recordPostedHeadline(%q): %v", postBody.RawTitle, err)
+  }
+  if imageHash != nil {
+    if err := recordPostedImage(*imageHash); err != nil {
+      log.Printf("recordPostedImage(): %v", err)
+    }
+  }
+
   log.Println("Message posted successfully!")
 }
 
@@ -123,8 +132,7 @@ func authenticate(identifier string, password string) (*AuthResponse, error) {
   }
   req.Header.Set("Content-Type", "application/json")
 
-  client := &http.Client{}
-  resp, err := client.Do(req)
+  resp, err := doWithRetry(req)
   if err != nil {
     return nil, fmt.Errorf("auth request failed: %w", err)
   }
@@ -147,23 +155,19 @@ func authenticate(identifier string, password string) (*AuthResponse, error) {
   return nil, fmt.Errorf("auth error (%d): %s - %s", resp.StatusCode, errResponse.Error, errResponse.Message)
 }
 
-func postMessage(accessToken, did, message string, imageBlob map[string]interface{}, imageName string) error {
+func postMessage(session *Session, message string, embed map[string]interface{}) error {
+  text := message + " " + autoHashtag
+  facets := buildFacets(context.Background(), text)
+
   postBody := map[string]interface{}{
-    "repo":       did,
+    "repo":       session.Did,
     "collection": "app.bsky.feed.post",
     "record": map[string]interface{}{
       "$type":     "app.bsky.feed.post",
-      "text":      message,
+      "text":      text,
+      "facets":    facets,
       "createdAt": time.Now().UTC().Format(time.RFC3339),
-      "embed": map[string]interface{}{
-        "$type": "app.bsky.embed.images",
-        "images": []map[string]interface{}{
-            map[string]interface{}{
-              "alt": imageName,
-              "image": imageBlob,
-            },
-        },
-      },
+      "embed":     embed,
     },
   }
   bodyBytes, err := json.Marshal(postBody)
@@ -176,11 +180,9 @@ func postMessage(accessToken, did, message string, imageBlob map[string]interfac
   if err != nil {
     return fmt.Errorf("failed to create post request: %w", err)
   }
-  req.Header.Set("Authorization", "Bearer "+accessToken)
   req.Header.Set("Content-Type", "application/json")
 
-  client := &http.Client{}
-  resp, err := client.Do(req)
+  resp, err := session.Do(req)
   if err != nil {
     return fmt.Errorf("post request failed: %w", err)
   }
@@ -198,16 +200,14 @@ func postMessage(accessToken, did, message string, imageBlob map[string]interfac
   return fmt.Errorf("post error (%d): %s - %s", resp.StatusCode, errResponse.Error, errResponse.Message)
 }
 
-func uploadImage(accessToken, did string, imageData []byte) (map[string]interface{}, error) {
+func uploadImage(session *Session, imageData []byte, contentType string) (map[string]interface{}, error) {
   req, err := http.NewRequest("POST", uploadImageURL, bytes.NewBuffer(imageData))
   if err != nil {
     return nil, fmt.Errorf("failed to upload image: %w", err)
   }
-  req.Header.Set("Authorization", "Bearer "+accessToken)
-  req.Header.Set("Content-Type", "image/jpg")
+  req.Header.Set("Content-Type", contentType)
 
-  client := &http.Client{}
-  resp, err := client.Do(req)
+  resp, err := session.Do(req)
   if err != nil {
     return nil, fmt.Errorf("post request failed: %w", err)
   }
@@ -236,72 +236,158 @@ func uploadImage(accessToken, did string, imageData []byte) (map[string]interfac
 
 // =======================================================================
 
-var sourcesRSS = []string {
-  "https://www.sciencedaily.com/rss/computers_math/artificial_intelligence.xml", // Science Daily AI
-  "https://feeds.a.dj.com/rss/RSSWSJD.xml", // WSJ Tech news
-  "https://www.engadget.com/rss.xml", // Engadget
-  "https://rss.nytimes.com/services/xml/rss/nyt/Technology.xml", // NYT tech
-  "https://www.reutersagency.com/feed/?best-topics=tech&post_type=best", // Reuters tech
-}
+const (
+  // feedsConfigPathEnv names the env var pointing at the feeds config file.
+  feedsConfigPathEnv = "FEEDS_CONFIG_PATH"
+  defaultFeedsConfig = "./feeds.yaml"
+  feedFetchTimeout   = 10 * time.Second
+)
+
 var aiRegex = regexp.MustCompile(`\b(AI|A\.I|Artificial Intelligence|artificial intelligence)\b`)
 
-type Rss struct {
-    Ch RssChannel `xml:"channel"`
-}
-type RssChannel struct {
-    Item []RssItem `xml:"item"`
+func feedsConfigPath() string {
+  if path := os.Getenv(feedsConfigPathEnv); path != "" {
+    return path
+  }
+  return defaultFeedsConfig
 }
-type RssItem struct {
-    Title string `xml:"title"`
+
+// postCandidate is the chosen headline for a single run, along with the
+// original article link needed to build a rich-link embed.
+type postCandidate struct {
+  Body     string // post text, with "AI" normalized to "Al"
+  RawTitle string // original headline, recorded to the dedup history
+  Link     string // article URL the headline linked to
 }
 
-func getPostBody() (string, error) {
-  aiTitles := []string{}
-  for _, srcRSS := range sourcesRSS {
-    // Get it
-    resp, err := http.Get(srcRSS)
-    if err != nil {
-      log.Printf("http.Get(%q): %v", srcRSS, err)
+func getPostBody() (*postCandidate, error) {
+  cfg, err := feeds.LoadConfig(feedsConfigPath())
+  if err != nil {
+    return nil, fmt.Errorf("feeds.LoadConfig() = %w", err)
+  }
+
+  ctx, cancel := context.WithTimeout(context.Background(), feedFetchTimeout)
+  defer cancel()
+
+  var (
+    mu      sync.Mutex
+    wg      sync.WaitGroup
+    aiItems []weightedItem
+  )
+  for _, sc := range cfg.Sources {
+    if !sc.IsEnabled() {
       continue
     }
-    defer resp.Body.Close()
-    respBody, err := io.ReadAll(resp.Body)
+    source, err := feeds.NewSource(sc)
     if err != nil {
-      log.Printf("io.ReadAll(%q): %v", srcRSS, err)
+      log.Printf("feeds.NewSource(%q): %v", sc.URL, err)
       continue
     }
-
-    // Parse the xml
-    var rss Rss
-    if err := xml.Unmarshal(respBody, &rss); err != nil {
-      log.Printf("xml.Unmarshal(%q): %v", srcRSS, err)
-      continue
+    keywordRegex := aiRegex
+    if sc.KeywordRegex != "" {
+      re, err := regexp.Compile(sc.KeywordRegex)
+      if err != nil {
+        log.Printf("regexp.Compile(%q): %v", sc.KeywordRegex, err)
+      } else {
+        keywordRegex = re
+      }
     }
 
-    // Find a title that contains AI
-    for _, item := range rss.Ch.Item {
-      match := aiRegex.FindString(item.Title)
-      if match == "" {
-        continue
+    wg.Add(1)
+    go func(sc feeds.SourceConfig, source feeds.Source, keywordRegex *regexp.Regexp) {
+      defer wg.Done()
+      items, err := source.Fetch(ctx)
+      if err != nil {
+        log.Printf("Fetch(%q): %v", sc.URL, err)
+        return
+      }
+
+      // Find items whose title contains AI (or the source's custom keyword)
+      weight := sc.Weight
+      if weight <= 0 {
+        weight = 1
       }
-      aiTitles = append(aiTitles, item.Title)
+      var matched []weightedItem
+      for _, item := range items {
+        if keywordRegex.FindString(item.Title) == "" {
+          continue
+        }
+        matched = append(matched, weightedItem{Item: item, Weight: weight})
+      }
+
+      mu.Lock()
+      aiItems = append(aiItems, matched...)
+      mu.Unlock()
+    }(sc, source, keywordRegex)
+  }
+  wg.Wait()
+
+  log.Printf("Found %v matching possible titles", len(aiItems))
+  if len(aiItems) == 0 {
+    return nil, fmt.Errorf("Found no AI titles somehow")
+  }
+
+  // Dedup based on recently posted headlines
+  history, err := loadHistory(context.Background())
+  if err != nil {
+    log.Printf("loadHistory() = %v", err)
+    history = &postHistory{}
+  }
+  candidates := make([]weightedItem, 0, len(aiItems))
+  for _, item := range aiItems {
+    if !history.isDuplicateHeadline(item.Title) {
+      candidates = append(candidates, item)
     }
   }
+  if len(candidates) == 0 {
+    return nil, fmt.Errorf("Found no un-posted AI titles somehow")
+  }
+
+  // Pick one at random, biased toward higher-weight sources
+  item := pickWeighted(candidates)
+  return &postCandidate{
+    Body:     aiRegex.ReplaceAllLiteralString(item.Title, "Al"),
+    RawTitle: item.Title,
+    Link:     item.Link,
+  }, nil
+}
+
+// weightedItem pairs a feed item with its source's selection weight, so
+// getPostBody() can bias the final pick toward higher-weight sources without
+// threading the weight through the feeds package itself.
+type weightedItem struct {
+  feeds.Item
+  Weight int
+}
 
-  log.Printf("Found %v matching possible titles", len(aiTitles))
-  if len(aiTitles) == 0 {
-    return "", fmt.Errorf("Found no AI titles somehow")
+// pickWeighted picks a random item from items, biased proportionally to each
+// item's Weight (e.g. an item with weight 3 is 3x as likely to be picked as
+// one with weight 1).
+func pickWeighted(items []weightedItem) feeds.Item {
+  total := 0
+  for _, item := range items {
+    total += item.Weight
+  }
+  if total <= 0 {
+    return items[rand.Intn(len(items))].Item
   }
 
-  // Pick one at random
-  // TODO: Dedup based on recently posted headlines
-  title := aiTitles[rand.Intn(len(aiTitles))]
-  return aiRegex.ReplaceAllLiteralString(title, "Al"), nil
+  r := rand.Intn(total)
+  for _, item := range items {
+    r -= item.Weight
+    if r < 0 {
+      return item.Item
+    }
+  }
+  return items[len(items)-1].Item
 }
 
 func getImage() ([]byte, string, error) {
     images := []string{}
     err := filepath.Walk("./images", func(path string, info os.FileInfo, err error) error {
+        if err != nil {
+            return nil
+        }
         if strings.HasSuffix(info.Name(), ".jpg") {
             images = append(images, path)
         }
@@ -313,8 +399,29 @@ func getImage() ([]byte, string, error) {
     if len(images) == 0 {
       return nil, "", fmt.Errorf("Found no images")
     }
-    
-    imageFile := images[rand.Intn(len(images))]
+
+    // Avoid picking an image that looks like one we posted recently.
+    hashes := loadImageHashCache()
+    history, err := loadHistory(context.Background())
+    if err != nil {
+      log.Printf("loadHistory() = %v", err)
+      history = &postHistory{}
+    }
+    threshold := imageHashThreshold()
+
+    candidates := make([]string, 0, len(images))
+    for _, path := range images {
+      hash, ok := hashes[path]
+      if !ok || !history.isRecentImage(hash, threshold) {
+        candidates = append(candidates, path)
+      }
+    }
+    if len(candidates) == 0 {
+      log.Printf("All %d images look like recent posts (threshold %d); picking from the full set", len(images), threshold)
+      candidates = images
+    }
+
+    imageFile := candidates[rand.Intn(len(candidates))]
     data, err := os.ReadFile(imageFile)
     return data, strings.Split(imageFile, ".")[0], err
 }
@@ -0,0 +1,170 @@
+package main
+
+import (
+  "context"
+  "fmt"
+  "html"
+  "io"
+  "log"
+  "net/http"
+  "os"
+  "regexp"
+  "time"
+)
+
+const (
+  // ogEmbedEnabledEnv opts out of the rich-link embed path; set to "false" to
+  // always use the local-image fallback.
+  ogEmbedEnabledEnv = "OG_EMBED_ENABLED"
+  ogFetchTimeout    = 10 * time.Second
+)
+
+var (
+  ogTitleRegex = regexp.MustCompile(`(?is)<meta[^>]+property=["']og:title["'][^>]*content=["']([^"']*)["']`)
+  ogDescRegex  = regexp.MustCompile(`(?is)<meta[^>]+property=["']og:description["'][^>]*content=["']([^"']*)["']`)
+  ogImageRegex = regexp.MustCompile(`(?is)<meta[^>]+property=["']og:image["'][^>]*content=["']([^"']*)["']`)
+)
+
+// ogEmbedEnabled reports whether the OpenGraph rich-link embed path is enabled.
+func ogEmbedEnabled() bool {
+  return os.Getenv(ogEmbedEnabledEnv) != "false"
+}
+
+// openGraph holds the subset of a page's OpenGraph metadata we care about.
+type openGraph struct {
+  Title       string
+  Description string
+  Image       string
+}
+
+// fetchOpenGraph follows link and scrapes its og:title/og:description/og:image meta tags.
+func fetchOpenGraph(ctx context.Context, link string) (*openGraph, error) {
+  body, err := httpGet(ctx, link)
+  if err != nil {
+    return nil, err
+  }
+
+  og := &openGraph{
+    Title:       ogTag(ogTitleRegex, body),
+    Description: ogTag(ogDescRegex, body),
+    Image:       ogTag(ogImageRegex, body),
+  }
+  if og.Title == "" && og.Image == "" {
+    return nil, fmt.Errorf("no OpenGraph metadata found at %q", link)
+  }
+  return og, nil
+}
+
+func ogTag(re *regexp.Regexp, body []byte) string {
+  match := re.FindSubmatch(body)
+  if match == nil {
+    return ""
+  }
+  return html.UnescapeString(string(match[1]))
+}
+
+// httpGet performs a context-aware GET and returns the response body.
+func httpGet(ctx context.Context, url string) ([]byte, error) {
+  req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+  if err != nil {
+    return nil, fmt.Errorf("http.NewRequestWithContext(%q) = %w", url, err)
+  }
+  resp, err := http.DefaultClient.Do(req)
+  if err != nil {
+    return nil, fmt.Errorf("http.Get(%q) = %w", url, err)
+  }
+  defer resp.Body.Close()
+  return io.ReadAll(resp.Body)
+}
+
+// buildExternalEmbed follows link, scrapes its OpenGraph metadata, and uploads
+// the og:image (if any) to build an app.bsky.embed.external record.
+func buildExternalEmbed(ctx context.Context, session *Session, link string) (map[string]interface{}, error) {
+  og, err := fetchOpenGraph(ctx, link)
+  if err != nil {
+    return nil, fmt.Errorf("fetchOpenGraph(%q) = %w", link, err)
+  }
+
+  external := map[string]interface{}{
+    "uri":         link,
+    "title":       og.Title,
+    "description": og.Description,
+  }
+  if og.Image != "" {
+    imageData, err := httpGet(ctx, og.Image)
+    if err != nil {
+      log.Printf("fetch og:image %q: %v", og.Image, err)
+    } else if prepared, err := prepareImage(imageData); err != nil {
+      log.Printf("prepareImage() for og:image %q: %v", og.Image, err)
+    } else if thumb, err := uploadImage(session, prepared.Data, prepared.ContentType); err != nil {
+      log.Printf("uploadImage() for og:image %q: %v", og.Image, err)
+    } else {
+      external["thumb"] = thumb
+    }
+  }
+
+  return map[string]interface{}{
+    "$type":    "app.bsky.embed.external",
+    "external": external,
+  }, nil
+}
+
+// buildImagesEmbed builds the app.bsky.embed.images record for a single local
+// image, including the aspectRatio Bluesky needs to render it without letterboxing.
+func buildImagesEmbed(imageBlob map[string]interface{}, imageName string, width, height int) map[string]interface{} {
+  return map[string]interface{}{
+    "$type": "app.bsky.embed.images",
+    "images": []map[string]interface{}{
+      {
+        "alt":   imageName,
+        "image": imageBlob,
+        "aspectRatio": map[string]interface{}{
+          "width":  width,
+          "height": height,
+        },
+      },
+    },
+  }
+}
+
+// buildEmbed picks the richest embed available for the chosen headline: an
+// OpenGraph card for the article when link scraping succeeds (and isn't
+// disabled via OG_EMBED_ENABLED=false), falling back to a random local image
+// otherwise. It returns the local image's average hash when one was used, so
+// the caller can record it to the recent-image history.
+func buildEmbed(session *Session, link string) (map[string]interface{}, *uint64, error) {
+  if ogEmbedEnabled() && link != "" {
+    ctx, cancel := context.WithTimeout(context.Background(), ogFetchTimeout)
+    embed, err := buildExternalEmbed(ctx, session, link)
+    cancel()
+    if err != nil {
+      log.Printf("buildExternalEmbed(%q): %v; falling back to local image", link, err)
+    } else {
+      return embed, nil, nil
+    }
+  }
+
+  imageData, imageName, err := getImage()
+  if err != nil {
+    return nil, nil, fmt.Errorf("getImage() = %w", err)
+  }
+
+  prepared, err := prepareImage(imageData)
+  if err != nil {
+    return nil, nil, fmt.Errorf("prepareImage() = %w", err)
+  }
+  log.Printf("Uploading %v byte image of %v", len(prepared.Data), imageName)
+  imageBlob, err := uploadImage(session, prepared.Data, prepared.ContentType)
+  if err != nil {
+    return nil, nil, fmt.Errorf("uploadImage() = %w", err)
+  }
+
+  var hash *uint64
+  if h, err := averageHash(imageData); err != nil {
+    log.Printf("averageHash(%q): %v", imageName, err)
+  } else {
+    hash = &h
+  }
+
+  return buildImagesEmbed(imageBlob, imageName, prepared.Width, prepared.Height), hash, nil
+}
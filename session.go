@@ -0,0 +1,286 @@
+package main
+
+import (
+  "bytes"
+  "context"
+  "encoding/base64"
+  "encoding/json"
+  "fmt"
+  "io"
+  "log"
+  "net/http"
+  "os"
+  "strings"
+  "time"
+)
+
+const (
+  sessionLocalPath  = "./session.json"
+  sessionBucketEnv  = "SESSION_BUCKET"
+  sessionObjectName = "session.json"
+
+  refreshSessionURL = "https://bsky.social/xrpc/com.atproto.server.refreshSession"
+
+  retryAttempts  = 3
+  retryBaseDelay = 1 * time.Second
+)
+
+// Session caches a Bluesky accessJwt/refreshJwt pair across invocations -
+// important on Cloud Run, where otherwise every request would call
+// createSession from scratch - and knows how to refresh itself.
+type Session struct {
+  Did          string    `json:"did"`
+  AccessJwt    string    `json:"accessJwt"`
+  RefreshJwt   string    `json:"refreshJwt"`
+  AccessExpiry time.Time `json:"accessExpiry"`
+
+  // generation is the GCS object generation this session was loaded at (0 if
+  // loaded from local disk, or if no session was cached yet). save() passes
+  // it to writeGCSObject as a precondition so two overlapping invocations
+  // that both re-authenticate don't silently clobber each other's session;
+  // the loser logs the conflict and keeps using its in-memory session.
+  generation int64
+}
+
+// GetSession returns a usable session, preferring a cached one: if the cached
+// access token is still valid it's returned as-is; if it's expired, it's
+// refreshed; only if there's no cached session or the refresh fails does this
+// fall back to a fresh createSession call.
+func GetSession(ctx context.Context, identifier, password string) (*Session, error) {
+  session, err := loadSession(ctx)
+  if err != nil {
+    log.Printf("loadSession() = %v", err)
+    session = nil
+  }
+
+  if session != nil {
+    if time.Now().UTC().Before(session.AccessExpiry) {
+      return session, nil
+    }
+    if refreshed, err := session.refresh(ctx); err == nil {
+      return refreshed, nil
+    } else {
+      log.Printf("refresh() = %v; re-authenticating", err)
+    }
+  }
+
+  authResponse, err := authenticate(identifier, password)
+  if err != nil {
+    return nil, fmt.Errorf("authenticate() = %w", err)
+  }
+  var generation int64
+  if session != nil {
+    generation = session.generation
+  }
+  session = &Session{
+    Did:          authResponse.Did,
+    AccessJwt:    authResponse.AccessJwt,
+    RefreshJwt:   authResponse.RefreshJwt,
+    AccessExpiry: jwtExpiry(authResponse.AccessJwt),
+    generation:   generation,
+  }
+  if err := session.save(ctx); err != nil {
+    log.Printf("session.save() = %v", err)
+  }
+  return session, nil
+}
+
+// refresh exchanges the session's refresh token for a new accessJwt/refreshJwt
+// pair via com.atproto.server.refreshSession, and persists the result.
+func (s *Session) refresh(ctx context.Context) (*Session, error) {
+  req, err := http.NewRequestWithContext(ctx, http.MethodPost, refreshSessionURL, nil)
+  if err != nil {
+    return nil, fmt.Errorf("http.NewRequestWithContext() = %w", err)
+  }
+  req.Header.Set("Authorization", "Bearer "+s.RefreshJwt)
+
+  resp, err := doWithRetry(req)
+  if err != nil {
+    return nil, fmt.Errorf("refreshSession request failed: %w", err)
+  }
+  defer resp.Body.Close()
+
+  if resp.StatusCode != http.StatusOK {
+    var errResponse ErrorResponse
+    if err := json.NewDecoder(resp.Body).Decode(&errResponse); err != nil {
+      return nil, fmt.Errorf("failed to decode error response: %w", err)
+    }
+    return nil, fmt.Errorf("refreshSession error (%d): %s - %s", resp.StatusCode, errResponse.Error, errResponse.Message)
+  }
+
+  var refreshResponse struct {
+    Did        string `json:"did"`
+    AccessJwt  string `json:"accessJwt"`
+    RefreshJwt string `json:"refreshJwt"`
+  }
+  if err := json.NewDecoder(resp.Body).Decode(&refreshResponse); err != nil {
+    return nil, fmt.Errorf("failed to decode refreshSession response: %w", err)
+  }
+
+  refreshed := &Session{
+    Did:          refreshResponse.Did,
+    AccessJwt:    refreshResponse.AccessJwt,
+    RefreshJwt:   refreshResponse.RefreshJwt,
+    AccessExpiry: jwtExpiry(refreshResponse.AccessJwt),
+    generation:   s.generation,
+  }
+  if err := refreshed.save(ctx); err != nil {
+    log.Printf("session.save() = %v", err)
+  }
+  return refreshed, nil
+}
+
+// Do sends req with the session's current access token, transparently
+// refreshing the session and retrying once if the response is a 401
+// ExpiredToken, and retrying with exponential backoff on network errors and
+// 5xx responses (see doWithRetry).
+func (s *Session) Do(req *http.Request) (*http.Response, error) {
+  req.Header.Set("Authorization", "Bearer "+s.AccessJwt)
+
+  resp, err := doWithRetry(req)
+  if err != nil {
+    return nil, err
+  }
+  if resp.StatusCode != http.StatusUnauthorized {
+    return resp, nil
+  }
+
+  body, err := io.ReadAll(resp.Body)
+  resp.Body.Close()
+  if err != nil {
+    return nil, fmt.Errorf("failed to read 401 response body: %w", err)
+  }
+  var errResponse ErrorResponse
+  json.Unmarshal(body, &errResponse)
+  if errResponse.Error != "ExpiredToken" {
+    resp.Body = io.NopCloser(bytes.NewReader(body))
+    return resp, nil
+  }
+
+  refreshed, err := s.refresh(req.Context())
+  if err != nil {
+    return nil, fmt.Errorf("session refresh after 401 failed: %w", err)
+  }
+  *s = *refreshed
+
+  retryReq := req
+  if req.GetBody != nil {
+    retryBody, err := req.GetBody()
+    if err != nil {
+      return nil, fmt.Errorf("GetBody() = %w", err)
+    }
+    retryReq = req.Clone(req.Context())
+    retryReq.Body = retryBody
+  }
+  retryReq.Header.Set("Authorization", "Bearer "+s.AccessJwt)
+  return doWithRetry(retryReq)
+}
+
+// doWithRetry executes req, making up to retryAttempts attempts in total with
+// exponential backoff between them (1s, then 2s) on network errors and 5xx
+// responses.
+func doWithRetry(req *http.Request) (*http.Response, error) {
+  var lastErr error
+  delay := retryBaseDelay
+  for attempt := 0; attempt < retryAttempts; attempt++ {
+    if attempt > 0 {
+      if req.GetBody != nil {
+        body, err := req.GetBody()
+        if err != nil {
+          return nil, fmt.Errorf("GetBody() = %w", err)
+        }
+        retryReq := req.Clone(req.Context())
+        retryReq.Body = body
+        req = retryReq
+      }
+      time.Sleep(delay)
+      delay *= 2
+    }
+
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+      lastErr = err
+      log.Printf("request attempt %d/%d failed: %v", attempt+1, retryAttempts, err)
+      continue
+    }
+    if resp.StatusCode >= 500 {
+      resp.Body.Close()
+      lastErr = fmt.Errorf("server error: %d", resp.StatusCode)
+      log.Printf("request attempt %d/%d got %d", attempt+1, retryAttempts, resp.StatusCode)
+      continue
+    }
+    return resp, nil
+  }
+  return nil, lastErr
+}
+
+// jwtExpiry decodes a JWT's exp claim without verifying its signature - we
+// only need to know when our own access token will expire.
+func jwtExpiry(token string) time.Time {
+  parts := strings.Split(token, ".")
+  if len(parts) != 3 {
+    return time.Time{}
+  }
+  payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+  if err != nil {
+    return time.Time{}
+  }
+  var claims struct {
+    Exp int64 `json:"exp"`
+  }
+  if err := json.Unmarshal(payload, &claims); err != nil {
+    return time.Time{}
+  }
+  return time.Unix(claims.Exp, 0).UTC()
+}
+
+func (s *Session) save(ctx context.Context) error {
+  data, err := json.Marshal(s)
+  if err != nil {
+    return fmt.Errorf("failed to encode session: %w", err)
+  }
+  return writeSessionStore(ctx, data, s.generation)
+}
+
+// loadSession reads the cached session, returning (nil, nil) if none is cached yet.
+func loadSession(ctx context.Context) (*Session, error) {
+  data, generation, err := readSessionStore(ctx)
+  if err != nil {
+    return nil, fmt.Errorf("readSessionStore() = %w", err)
+  }
+  if len(data) == 0 {
+    return nil, nil
+  }
+  session := Session{generation: generation}
+  if err := json.Unmarshal(data, &session); err != nil {
+    return nil, fmt.Errorf("failed to decode cached session: %w", err)
+  }
+  return &session, nil
+}
+
+// readSessionStore loads the raw session bytes (and, for the GCS path, the
+// object generation they were read at) from local disk in dev, or from GCS
+// when running in production (Cloud Run).
+func readSessionStore(ctx context.Context) ([]byte, int64, error) {
+  if os.Getenv("ENVIRONMENT") == "production" {
+    return readGCSObject(ctx, os.Getenv(sessionBucketEnv), sessionObjectName)
+  }
+  data, err := os.ReadFile(sessionLocalPath)
+  if os.IsNotExist(err) {
+    return nil, 0, nil
+  }
+  return data, 0, err
+}
+
+// writeSessionStore persists the raw session bytes to local disk in dev, or
+// to GCS when running in production (Cloud Run). The GCS write uses
+// generation as a precondition (see writeGCSObject) so two overlapping
+// invocations that both re-authenticate can't silently clobber each other's
+// session; a losing writer just logs errGenerationConflict and keeps using
+// the session it already has in memory.
+func writeSessionStore(ctx context.Context, data []byte, generation int64) error {
+  if os.Getenv("ENVIRONMENT") == "production" {
+    return writeGCSObject(ctx, os.Getenv(sessionBucketEnv), sessionObjectName, data, generation)
+  }
+  return os.WriteFile(sessionLocalPath, data, 0600)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+  "encoding/base64"
+  "testing"
+  "time"
+)
+
+func TestJwtExpiry(t *testing.T) {
+  payload := base64.RawURLEncoding.EncodeToString([]byte(`{"exp":1700000000}`))
+  token := "header." + payload + ".signature"
+
+  want := time.Unix(1700000000, 0).UTC()
+  if got := jwtExpiry(token); !got.Equal(want) {
+    t.Errorf("jwtExpiry(valid token) = %v, want %v", got, want)
+  }
+}
+
+func TestJwtExpiryMalformed(t *testing.T) {
+  tests := []struct {
+    name  string
+    token string
+  }{
+    {"too few segments", "onlyonepart"},
+    {"invalid base64 payload", "header.not!valid!base64.signature"},
+    {"payload not JSON", "header." + base64.RawURLEncoding.EncodeToString([]byte("not json")) + ".signature"},
+  }
+  for _, tt := range tests {
+    if got := jwtExpiry(tt.token); !got.IsZero() {
+      t.Errorf("%s: jwtExpiry(%q) = %v, want zero time", tt.name, tt.token, got)
+    }
+  }
+}